@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,9 +15,10 @@ import (
 )
 
 type LogStream struct {
-	Level   Level
-	Labels  map[string]string
-	Entries []*LogEntry
+	Level    Level
+	Labels   map[string]string
+	Entries  []*LogEntry
+	TenantID string
 }
 
 type LogEntry struct {
@@ -23,10 +26,12 @@ type LogEntry struct {
 	Level     Level
 	Timestamp time.Time
 	LogLine   []byte
+	TenantID  string
 }
 
 const (
 	logLevelForcedLabel = "logLevel"
+	tenantIDHeader      = "X-Scope-OrgID"
 )
 
 type StreamsExchanger interface {
@@ -38,6 +43,33 @@ type BasicAuthExchanger interface {
 	SetBasicAuth(username, password string)
 }
 
+// TenantExchanger is implemented by exchangers that can talk to a
+// multi-tenant Loki deployment, which requires an X-Scope-OrgID header on
+// every push/ready request.
+type TenantExchanger interface {
+	SetTenantID(id string)
+}
+
+// BearerAuthExchanger is implemented by exchangers that can authenticate
+// with a bearer token instead of (or in addition to) basic auth, e.g. a
+// Grafana Cloud API key.
+type BearerAuthExchanger interface {
+	SetBearerToken(token string)
+}
+
+// HeadersExchanger is implemented by exchangers that can send arbitrary
+// extra headers on every request, e.g. for a proxy token in front of Loki.
+type HeadersExchanger interface {
+	SetHeaders(headers map[string]string)
+}
+
+// RetryConfigExchanger is implemented by exchangers that retry failed
+// pushes and can report batches they ultimately gave up on.
+type RetryConfigExchanger interface {
+	SetRetryPolicy(policy RetryPolicy)
+	SetOnDropped(fn OnDroppedFunc)
+}
+
 // Creates a client with direct send logic (nor batch neither queue) capable to
 // exchange with Loki v1 API via JSON
 //
@@ -47,9 +79,30 @@ func NewJSONv1Exchanger(lokiAddress string, useGzipCompression bool) StreamsExch
 		restClient:         &http.Client{},
 		lokiAddress:        lokiAddress,
 		useGzipCompression: useGzipCompression,
+		retryPolicy:        defaultRetryPolicy(),
+		fieldExtractor:     &jsonFieldExtractor{},
 	}
 }
 
+// Creates a JSON v1 exchanger whose http.Client is configured for mTLS,
+// matching the config surface real Loki clients expose (e.g. Grafana Cloud
+// or a self-hosted Loki behind a client-certificate-verifying proxy).
+func NewJSONv1ExchangerWithTLS(lokiAddress string, tlsCfg *tls.Config, useGzipCompression bool) StreamsExchanger {
+	return &lokiJsonV1Exchanger{
+		restClient: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsCfg},
+		},
+		lokiAddress:        lokiAddress,
+		useGzipCompression: useGzipCompression,
+		retryPolicy:        defaultRetryPolicy(),
+		fieldExtractor:     &jsonFieldExtractor{},
+	}
+}
+
+// defaultSendBatchTimeout bounds how long a Ping is allowed to wait for Loki
+// to respond. Push requests are not bound by it.
+const defaultSendBatchTimeout = 10 * time.Second
+
 const (
 	requestTimeout = defaultSendBatchTimeout
 )
@@ -60,6 +113,12 @@ type lokiJsonV1Exchanger struct {
 	lokiAddress        string
 	username           string
 	password           string
+	tenantID           string
+	bearerToken        string
+	headers            map[string]string
+	retryPolicy        RetryPolicy
+	onDropped          OnDroppedFunc
+	fieldExtractor     *jsonFieldExtractor
 }
 
 // Data transfer objects are restored from `push API` description:
@@ -91,12 +150,27 @@ type (
 )
 
 func (rcv *lokiJsonV1Exchanger) Push(streams []*LogStream) error {
-	var buf bytes.Buffer
+	var errs []error
+
+	// Every tenant gets pushed, even if an earlier one fails: a transient
+	// error for one tenant must not cause another tenant's batch to be
+	// silently skipped (and never handed to onDropped).
+	for tenantID, tenantStreams := range groupStreamsByTenant(streams, rcv.tenantID) {
+		if err := rcv.pushForTenant(tenantID, tenantStreams); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (rcv *lokiJsonV1Exchanger) pushForTenant(tenantID string, streams []*LogStream) error {
+	var payload bytes.Buffer
 
 	func() error {
-		var w io.Writer = &buf
+		var w io.Writer = &payload
 		if rcv.useGzipCompression {
-			gw := gzip.NewWriter(&buf)
+			gw := gzip.NewWriter(&payload)
 			defer gw.Close()
 			w = gw
 		}
@@ -106,13 +180,37 @@ func (rcv *lokiJsonV1Exchanger) Push(streams []*LogStream) error {
 		return nil
 	}()
 
-	req, err := http.NewRequest(
-		http.MethodPost,
-		rcv.lokiAddress+"/loki/api/v1/push",
-		&buf,
-	)
+	var lastErr error
+	for attempt := 0; attempt <= rcv.retryPolicy.MaxRetries; attempt++ {
+		retryable, retryDelay, err := rcv.doPush(tenantID, bytes.NewReader(payload.Bytes()))
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !retryable || attempt == rcv.retryPolicy.MaxRetries {
+			break
+		}
+
+		if retryDelay <= 0 {
+			retryDelay = nextBackoff(rcv.retryPolicy, attempt)
+		}
+		time.Sleep(retryDelay)
+	}
+
+	if rcv.onDropped != nil {
+		rcv.onDropped(streams, lastErr)
+	}
+	return lastErr
+}
+
+// doPush issues a single push attempt. It reports whether the failure (if
+// any) is worth retrying and, for 429/503-style throttling, how long the
+// server asked callers to wait via Retry-After.
+func (rcv *lokiJsonV1Exchanger) doPush(tenantID string, body io.Reader) (retryable bool, retryDelay time.Duration, err error) {
+	req, err := http.NewRequest(http.MethodPost, rcv.lokiAddress+"/loki/api/v1/push", body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %s", err)
+		return false, 0, fmt.Errorf("failed to create request: %s", err)
 	}
 
 	req.Header.Add("Content-Type", "application/json")
@@ -120,23 +218,32 @@ func (rcv *lokiJsonV1Exchanger) Push(streams []*LogStream) error {
 		req.Header.Add("Content-Encoding", "gzip")
 	}
 
-	if rcv.username != "" && rcv.password != "" {
-		req.SetBasicAuth(rcv.username, rcv.password)
+	if tenantID != "" {
+		req.Header.Set(tenantIDHeader, tenantID)
 	}
 
+	rcv.applyAuth(req)
+
 	resp, err := rcv.restClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send push message: %s", err)
+		return true, 0, fmt.Errorf("failed to send push message: %s", err)
 	}
 
 	defer func() { _ = resp.Body.Close() }()
 
 	if !rcv.isSuccessHTTPCode(resp.StatusCode) {
 		messageBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected response code [code=%d], message: %s",
+		err := fmt.Errorf("unexpected response code [code=%d], message: %s",
 			resp.StatusCode, string(messageBody))
+
+		if !isRetryableHTTPCode(resp.StatusCode) {
+			return false, 0, err
+		}
+
+		delay, _ := retryAfter(resp.Header)
+		return true, delay, err
 	}
-	return nil
+	return false, 0, nil
 }
 
 func (rcv *lokiJsonV1Exchanger) Ping() (*PongResponse, error) {
@@ -150,6 +257,12 @@ func (rcv *lokiJsonV1Exchanger) Ping() (*PongResponse, error) {
 		return nil, fmt.Errorf("unable to build ping request: %s", err)
 	}
 
+	if rcv.tenantID != "" {
+		pingRequest.Header.Set(tenantIDHeader, rcv.tenantID)
+	}
+
+	rcv.applyAuth(pingRequest)
+
 	resp, err := rcv.restClient.Do(pingRequest)
 	if err != nil {
 		return nil, fmt.Errorf("pong is not received: %s", err)
@@ -180,23 +293,30 @@ func (rcv *lokiJsonV1Exchanger) transformLogStreamsToDTO(streams []*LogStream) *
 			continue
 		}
 
-		lokiStream := &lokiDTOJsonV1Stream{
-			Stream: streams[i].Labels,
-			Values: make([][2]string, 0, len(streams[i].Entries)),
-		}
+		for _, group := range rcv.fieldExtractor.groupByExtractedLabels(streams[i].Entries) {
+			labels := streams[i].Labels
+			if len(group.extra) > 0 {
+				labels = copyAndMergeLabels(streams[i].Labels, group.extra)
+			}
 
-		for j := range streams[i].Entries {
-			if streams[i].Entries[j] == nil {
-				continue
+			lokiStream := &lokiDTOJsonV1Stream{
+				Stream: labels,
+				Values: make([][2]string, 0, len(group.entries)),
 			}
 
-			lokiStream.Values = append(lokiStream.Values, [2]string{
-				strconv.FormatInt(streams[i].Entries[j].Timestamp.UnixNano(), 10),
-				string(streams[i].Entries[j].LogLine),
-			})
-		}
+			for j := range group.entries {
+				if group.entries[j] == nil {
+					continue
+				}
 
-		pushRequest.Streams = append(pushRequest.Streams, lokiStream)
+				lokiStream.Values = append(lokiStream.Values, [2]string{
+					strconv.FormatInt(group.entries[j].Timestamp.UnixNano(), 10),
+					string(group.entries[j].LogLine),
+				})
+			}
+
+			pushRequest.Streams = append(pushRequest.Streams, lokiStream)
+		}
 	}
 
 	return pushRequest
@@ -207,6 +327,42 @@ func (rcv *lokiJsonV1Exchanger) SetBasicAuth(username, password string) {
 	rcv.password = password
 }
 
+func (rcv *lokiJsonV1Exchanger) SetTenantID(id string) {
+	rcv.tenantID = id
+}
+
+func (rcv *lokiJsonV1Exchanger) SetRetryPolicy(policy RetryPolicy) {
+	rcv.retryPolicy = policy
+}
+
+func (rcv *lokiJsonV1Exchanger) SetOnDropped(fn OnDroppedFunc) {
+	rcv.onDropped = fn
+}
+
+func (rcv *lokiJsonV1Exchanger) SetBearerToken(token string) {
+	rcv.bearerToken = token
+}
+
+func (rcv *lokiJsonV1Exchanger) SetHeaders(headers map[string]string) {
+	rcv.headers = headers
+}
+
+// applyAuth sets extra headers and authentication on req. A bearer token, if
+// set, takes precedence over basic auth since the two are mutually
+// exclusive on the Authorization header.
+func (rcv *lokiJsonV1Exchanger) applyAuth(req *http.Request) {
+	for k, v := range rcv.headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case rcv.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+rcv.bearerToken)
+	case rcv.username != "" && rcv.password != "":
+		req.SetBasicAuth(rcv.username, rcv.password)
+	}
+}
+
 func (rcv *lokiJsonV1Exchanger) isSuccessHTTPCode(code int) bool {
 	return 199 < code && code < 300
 }
@@ -0,0 +1,90 @@
+package promtail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPushFansOutPerTenantHeader checks that streams with different tenant
+// IDs land in separate push requests, each carrying its own X-Scope-OrgID,
+// rather than being merged into a single request under the default tenant.
+func TestPushFansOutPerTenantHeader(t *testing.T) {
+	seenTenants := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenTenants[r.Header.Get(tenantIDHeader)]++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rcv := &lokiJsonV1Exchanger{
+		restClient:     &http.Client{},
+		lokiAddress:    server.URL,
+		retryPolicy:    defaultRetryPolicy(),
+		fieldExtractor: &jsonFieldExtractor{},
+	}
+
+	streams := []*LogStream{
+		{
+			TenantID: "team-a",
+			Labels:   map[string]string{"job": "promtail"},
+			Entries:  []*LogEntry{{Timestamp: time.Unix(0, 1), LogLine: []byte("a")}},
+		},
+		{
+			TenantID: "team-b",
+			Labels:   map[string]string{"job": "promtail"},
+			Entries:  []*LogEntry{{Timestamp: time.Unix(0, 2), LogLine: []byte("b")}},
+		},
+	}
+
+	if err := rcv.Push(streams); err != nil {
+		t.Fatalf("Push: %s", err)
+	}
+
+	if len(seenTenants) != 2 {
+		t.Fatalf("saw %d distinct tenant headers, want 2: %v", len(seenTenants), seenTenants)
+	}
+	if seenTenants["team-a"] != 1 || seenTenants["team-b"] != 1 {
+		t.Fatalf("tenant request counts = %v, want one request each for team-a and team-b", seenTenants)
+	}
+}
+
+// TestApplyAuthPrecedenceOverTheWire checks that a bearer token wins over
+// basic auth on the Authorization header actually sent on the wire, and that
+// custom headers are always applied.
+func TestApplyAuthPrecedenceOverTheWire(t *testing.T) {
+	var gotAuth, gotCustom string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotCustom = r.Header.Get("X-Custom")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rcv := &lokiJsonV1Exchanger{
+		restClient:     &http.Client{},
+		lokiAddress:    server.URL,
+		retryPolicy:    defaultRetryPolicy(),
+		fieldExtractor: &jsonFieldExtractor{},
+	}
+	rcv.SetBasicAuth("user", "pass")
+	rcv.SetBearerToken("abc123")
+	rcv.SetHeaders(map[string]string{"X-Custom": "value"})
+
+	streams := []*LogStream{{
+		Labels:  map[string]string{"job": "promtail"},
+		Entries: []*LogEntry{{Timestamp: time.Unix(0, 1), LogLine: []byte("hello")}},
+	}}
+
+	if err := rcv.Push(streams); err != nil {
+		t.Fatalf("Push: %s", err)
+	}
+
+	if gotAuth != "Bearer abc123" {
+		t.Errorf("Authorization = %q, want bearer token to take precedence over basic auth", gotAuth)
+	}
+	if gotCustom != "value" {
+		t.Errorf("X-Custom = %q, want the configured custom header", gotCustom)
+	}
+}
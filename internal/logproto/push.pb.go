@@ -0,0 +1,92 @@
+// Package logproto contains a hand-vendored subset of Loki's push API types,
+// generated from push.proto. It only implements the Marshal side, which is
+// all the exchanger needs to ship streams to /loki/api/v1/push.
+//
+//	Read more at: https://github.com/grafana/loki/blob/main/pkg/push/push.proto
+package logproto
+
+import "time"
+
+type PushRequest struct {
+	Streams []*StreamAdapter
+}
+
+type StreamAdapter struct {
+	Labels  string
+	Entries []*EntryAdapter
+}
+
+type EntryAdapter struct {
+	Timestamp time.Time
+	Line      string
+}
+
+// Marshal encodes the request using the standard protobuf wire format.
+func (m *PushRequest) Marshal() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	var buf []byte
+	for _, stream := range m.Streams {
+		encoded, err := stream.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = appendTagAndBytes(buf, 1, encoded)
+	}
+	return buf, nil
+}
+
+func (m *StreamAdapter) Marshal() ([]byte, error) {
+	var buf []byte
+	buf = appendTagAndBytes(buf, 1, []byte(m.Labels))
+
+	for _, entry := range m.Entries {
+		encoded, err := entry.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = appendTagAndBytes(buf, 2, encoded)
+	}
+	return buf, nil
+}
+
+func (m *EntryAdapter) Marshal() ([]byte, error) {
+	var buf []byte
+	buf = appendTagAndBytes(buf, 1, marshalTimestamp(m.Timestamp))
+	buf = appendTagAndBytes(buf, 2, []byte(m.Line))
+	return buf, nil
+}
+
+// marshalTimestamp encodes a google.protobuf.Timestamp message (seconds in
+// field 1, nanos in field 2) matching the wire format Loki expects.
+func marshalTimestamp(t time.Time) []byte {
+	var buf []byte
+	if seconds := t.Unix(); seconds != 0 {
+		buf = appendTagAndVarint(buf, 1, uint64(seconds))
+	}
+	if nanos := t.Nanosecond(); nanos != 0 {
+		buf = appendTagAndVarint(buf, 2, uint64(nanos))
+	}
+	return buf
+}
+
+func appendTagAndBytes(buf []byte, fieldNum int, data []byte) []byte {
+	buf = appendVarint(buf, uint64(fieldNum)<<3|2)
+	buf = appendVarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendTagAndVarint(buf []byte, fieldNum int, v uint64) []byte {
+	buf = appendVarint(buf, uint64(fieldNum)<<3|0)
+	return appendVarint(buf, v)
+}
+
+func appendVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
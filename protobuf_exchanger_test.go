@@ -0,0 +1,19 @@
+package promtail
+
+import "testing"
+
+func TestFormatLabels(t *testing.T) {
+	got := formatLabels(map[string]string{"job": "promtail", "level": "info"})
+	want := `{job="promtail",level="info"}`
+	if got != want {
+		t.Fatalf("formatLabels = %q, want %q", got, want)
+	}
+}
+
+func TestFormatLabelsEscapesQuotesAndBackslashes(t *testing.T) {
+	got := formatLabels(map[string]string{"service": `a"b\c`})
+	want := `{service="a\"b\\c"}`
+	if got != want {
+		t.Fatalf("formatLabels = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package promtail
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how an exchanger retries a failed push. It applies to
+// network errors and 5xx/429 responses; other 4xx responses are treated as
+// permanent failures and are never retried.
+type RetryPolicy struct {
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxRetries int
+}
+
+// Defaults mirror what loki-canary uses for its push mode.
+const (
+	defaultRetryMinBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Minute
+	defaultRetryMaxRetries = 10
+)
+
+func defaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MinBackoff: defaultRetryMinBackoff,
+		MaxBackoff: defaultRetryMaxBackoff,
+		MaxRetries: defaultRetryMaxRetries,
+	}
+}
+
+// OnDroppedFunc is invoked once an exchanger gives up on a batch, either
+// because the response was a permanent failure or because the retry policy
+// was exhausted. Applications can use it to persist the batch to a
+// dead-letter file or forward it elsewhere instead of silently losing logs.
+type OnDroppedFunc func(streams []*LogStream, err error)
+
+func isRetryableHTTPCode(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// retryAfter returns the delay requested by a Retry-After response header,
+// if present and parseable, as either a number of seconds or an HTTP-date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when), true
+	}
+
+	return 0, false
+}
+
+// nextBackoff doubles the backoff on every attempt, starting from
+// policy.MinBackoff and capped at policy.MaxBackoff, with a small jitter to
+// avoid retry storms across many shippers. The result never exceeds
+// policy.MaxBackoff.
+func nextBackoff(policy RetryPolicy, attempt int) time.Duration {
+	backoff := policy.MinBackoff << attempt
+	if backoff <= 0 || backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(backoff)/4 + 1))
+	backoff -= jitter / 2
+
+	if backoff <= 0 {
+		return policy.MinBackoff
+	}
+	return backoff
+}
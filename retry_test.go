@@ -0,0 +1,178 @@
+package promtail
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableHTTPCode(t *testing.T) {
+	cases := map[int]bool{
+		200: false,
+		400: false,
+		404: false,
+		429: true,
+		500: true,
+		503: true,
+	}
+
+	for code, want := range cases {
+		if got := isRetryableHTTPCode(code); got != want {
+			t.Errorf("isRetryableHTTPCode(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "5")
+
+	delay, ok := retryAfter(header)
+	if !ok {
+		t.Fatal("expected Retry-After to be recognized")
+	}
+	if delay != 5*time.Second {
+		t.Fatalf("delay = %v, want 5s", delay)
+	}
+}
+
+func TestRetryAfterMissing(t *testing.T) {
+	if _, ok := retryAfter(http.Header{}); ok {
+		t.Fatal("expected no Retry-After to report ok=false")
+	}
+}
+
+func TestRetryAfterHTTPDate(t *testing.T) {
+	when := time.Now().Add(10 * time.Second).UTC()
+
+	header := http.Header{}
+	header.Set("Retry-After", when.Format(http.TimeFormat))
+
+	delay, ok := retryAfter(header)
+	if !ok {
+		t.Fatal("expected HTTP-date Retry-After to be recognized")
+	}
+	if delay <= 0 || delay > 11*time.Second {
+		t.Fatalf("delay = %v, want ~10s", delay)
+	}
+}
+
+func TestNextBackoffRespectsBounds(t *testing.T) {
+	policy := RetryPolicy{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+		MaxRetries: 10,
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		backoff := nextBackoff(policy, attempt)
+		if backoff <= 0 {
+			t.Fatalf("attempt %d: backoff must be positive, got %v", attempt, backoff)
+		}
+		if backoff > policy.MaxBackoff {
+			t.Fatalf("attempt %d: backoff %v exceeds MaxBackoff %v", attempt, backoff, policy.MaxBackoff)
+		}
+	}
+}
+
+// TestPushRetriesThenSucceeds exercises the retry loop through the real
+// Push wiring (doPush/nextBackoff/isRetryableHTTPCode together) rather than
+// its pure-function pieces in isolation: the fake server fails with a
+// retryable 503 twice before succeeding, and Push must keep retrying instead
+// of giving up after the first failure.
+func TestPushRetriesThenSucceeds(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rcv := &lokiJsonV1Exchanger{
+		restClient:  &http.Client{},
+		lokiAddress: server.URL,
+		retryPolicy: RetryPolicy{
+			MinBackoff: time.Millisecond,
+			MaxBackoff: time.Millisecond,
+			MaxRetries: 5,
+		},
+		fieldExtractor: &jsonFieldExtractor{},
+	}
+
+	streams := []*LogStream{{
+		Labels:  map[string]string{"job": "promtail"},
+		Entries: []*LogEntry{{Timestamp: time.Unix(0, 1), LogLine: []byte("hello")}},
+	}}
+
+	if err := rcv.Push(streams); err != nil {
+		t.Fatalf("Push returned an error after the server recovered: %s", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("server saw %d attempts, want 3 (2 failures + 1 success)", got)
+	}
+}
+
+// TestPushGivesUpOnPermanentFailure checks that a non-retryable 4xx response
+// is not retried and is reported to onDropped exactly once.
+func TestPushGivesUpOnPermanentFailure(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var dropped []*LogStream
+	rcv := &lokiJsonV1Exchanger{
+		restClient:     &http.Client{},
+		lokiAddress:    server.URL,
+		retryPolicy:    RetryPolicy{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, MaxRetries: 5},
+		fieldExtractor: &jsonFieldExtractor{},
+		onDropped: func(streams []*LogStream, err error) {
+			dropped = streams
+		},
+	}
+
+	streams := []*LogStream{{
+		Labels:  map[string]string{"job": "promtail"},
+		Entries: []*LogEntry{{Timestamp: time.Unix(0, 1), LogLine: []byte("hello")}},
+	}}
+
+	if err := rcv.Push(streams); err == nil {
+		t.Fatal("expected Push to return an error for a permanent failure")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Fatalf("server saw %d attempts, want 1 (no retry on a 4xx)", got)
+	}
+	if len(dropped) != 1 {
+		t.Fatalf("onDropped received %d streams, want 1", len(dropped))
+	}
+}
+
+func TestNextBackoffGrows(t *testing.T) {
+	policy := RetryPolicy{
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: time.Minute,
+		MaxRetries: 10,
+	}
+
+	// Jitter makes any single comparison flaky, so compare averages across
+	// a few samples instead of exact values.
+	avg := func(attempt int) time.Duration {
+		var total time.Duration
+		const samples = 20
+		for i := 0; i < samples; i++ {
+			total += nextBackoff(policy, attempt)
+		}
+		return total / samples
+	}
+
+	if avg(4) <= avg(0) {
+		t.Fatalf("expected backoff to grow with attempt count: attempt0=%v attempt4=%v", avg(0), avg(4))
+	}
+}
@@ -1,6 +1,13 @@
 package promtail
 
-import "maps"
+import (
+	"encoding/base64"
+	"maps"
+)
+
+func basicAuthHeader(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
 
 func copyLabels(src map[string]string) map[string]string {
 	dst := make(map[string]string, len(src))
@@ -8,6 +15,49 @@ func copyLabels(src map[string]string) map[string]string {
 	return dst
 }
 
+// groupStreamsByTenant splits streams into per-tenant batches so the caller
+// can issue one push request per tenant, each with its own X-Scope-OrgID
+// header. A per-entry TenantID wins over its stream's, which in turn wins
+// over defaultTenant.
+func groupStreamsByTenant(streams []*LogStream, defaultTenant string) map[string][]*LogStream {
+	grouped := make(map[string][]*LogStream)
+
+	for _, stream := range streams {
+		if stream == nil {
+			continue
+		}
+
+		streamTenant := stream.TenantID
+		if streamTenant == "" {
+			streamTenant = defaultTenant
+		}
+
+		entriesByTenant := make(map[string][]*LogEntry)
+		for _, entry := range stream.Entries {
+			if entry == nil {
+				continue
+			}
+
+			tenant := entry.TenantID
+			if tenant == "" {
+				tenant = streamTenant
+			}
+			entriesByTenant[tenant] = append(entriesByTenant[tenant], entry)
+		}
+
+		for tenant, entries := range entriesByTenant {
+			grouped[tenant] = append(grouped[tenant], &LogStream{
+				Level:    stream.Level,
+				Labels:   stream.Labels,
+				Entries:  entries,
+				TenantID: tenant,
+			})
+		}
+	}
+
+	return grouped
+}
+
 func copyAndMergeLabels(srcs ...map[string]string) map[string]string {
 	//
 	// We do assume, that gathering map's sizes in a single loop is faster
@@ -0,0 +1,58 @@
+package promtail
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGroupStreamsByTenant(t *testing.T) {
+	entryA := &LogEntry{LogLine: []byte("a")}
+	entryB := &LogEntry{LogLine: []byte("b"), TenantID: "tenant-b"}
+	entryC := &LogEntry{LogLine: []byte("c")}
+
+	streams := []*LogStream{
+		{
+			Labels:   map[string]string{"job": "promtail"},
+			Entries:  []*LogEntry{entryA, entryB},
+			TenantID: "tenant-a",
+		},
+		{
+			Labels:  map[string]string{"job": "other"},
+			Entries: []*LogEntry{entryC},
+		},
+	}
+
+	grouped := groupStreamsByTenant(streams, "default-tenant")
+
+	tenants := make([]string, 0, len(grouped))
+	for tenant := range grouped {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	if want := []string{"default-tenant", "tenant-a", "tenant-b"}; !reflect.DeepEqual(tenants, want) {
+		t.Fatalf("tenants = %v, want %v", tenants, want)
+	}
+
+	if got := len(grouped["tenant-a"][0].Entries); got != 1 || grouped["tenant-a"][0].Entries[0] != entryA {
+		t.Fatalf("tenant-a should only contain entryA, got %v", grouped["tenant-a"])
+	}
+
+	if got := len(grouped["tenant-b"][0].Entries); got != 1 || grouped["tenant-b"][0].Entries[0] != entryB {
+		t.Fatalf("tenant-b should only contain entryB (per-entry override), got %v", grouped["tenant-b"])
+	}
+
+	if got := len(grouped["default-tenant"][0].Entries); got != 1 || grouped["default-tenant"][0].Entries[0] != entryC {
+		t.Fatalf("default-tenant should only contain entryC, got %v", grouped["default-tenant"])
+	}
+}
+
+func TestGroupStreamsByTenantNilSafety(t *testing.T) {
+	streams := []*LogStream{nil, {Entries: []*LogEntry{nil}}}
+
+	grouped := groupStreamsByTenant(streams, "")
+	if len(grouped) != 0 {
+		t.Fatalf("expected no groups for nil stream/entries, got %v", grouped)
+	}
+}
@@ -0,0 +1,156 @@
+package promtail
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// StructuredFieldsExchanger is implemented by exchangers that can derive
+// Loki stream labels from structured (JSON) LogLine content instead of
+// requiring callers to pre-populate LogStream.Labels themselves.
+type StructuredFieldsExchanger interface {
+	SetStructuredLevelExtraction(fields ...string)
+	SetLabelFromJSONField(jsonField, labelName string)
+}
+
+// SetStructuredLevelExtraction inspects each LogEntry.LogLine as JSON and,
+// when one of fields is present, merges it into the stream labels as
+// logLevelForcedLabel before the streams are grouped for the push request.
+// With no fields given, it falls back to the "level"/"severity" fields used
+// by most structured loggers.
+func (rcv *lokiJsonV1Exchanger) SetStructuredLevelExtraction(fields ...string) {
+	if len(fields) == 0 {
+		fields = []string{"level", "severity"}
+	}
+	rcv.fieldExtractor.levelFields = fields
+}
+
+// SetLabelFromJSONField promotes an arbitrary structured field (service,
+// trace_id, etc.) from a JSON LogLine into a Loki stream label, without the
+// caller having to pre-process entries. It can be called multiple times to
+// promote more than one field.
+func (rcv *lokiJsonV1Exchanger) SetLabelFromJSONField(jsonField, labelName string) {
+	if rcv.fieldExtractor.labelFields == nil {
+		rcv.fieldExtractor.labelFields = make(map[string]string)
+	}
+	rcv.fieldExtractor.labelFields[jsonField] = labelName
+}
+
+// jsonFieldExtractor pulls configured fields out of structured JSON log
+// lines and turns them into extra stream labels.
+type jsonFieldExtractor struct {
+	levelFields []string
+	labelFields map[string]string // jsonField -> labelName
+}
+
+func (e *jsonFieldExtractor) isConfigured() bool {
+	return len(e.levelFields) > 0 || len(e.labelFields) > 0
+}
+
+// extractedGroup holds the entries, not necessarily adjacent in the batch,
+// that all extracted the same label values (possibly none).
+type extractedGroup struct {
+	extra   map[string]string
+	entries []*LogEntry
+}
+
+// groupByExtractedLabels splits entries into one group per distinct set of
+// extracted label values, preserving first-seen order. Entries can't share a
+// Loki stream unless their labels are identical, so a mixed-severity batch
+// yields one stream per level rather than mislabeling every line with
+// whichever value happened to be seen first.
+func (e *jsonFieldExtractor) groupByExtractedLabels(entries []*LogEntry) []extractedGroup {
+	if !e.isConfigured() {
+		return []extractedGroup{{entries: entries}}
+	}
+
+	order := make([]string, 0, len(entries))
+	groups := make(map[string]*extractedGroup, len(entries))
+
+	for _, entry := range entries {
+		extra := e.extractEntryLabels(entry)
+		key := labelsKey(extra)
+
+		group, ok := groups[key]
+		if !ok {
+			group = &extractedGroup{extra: extra}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.entries = append(group.entries, entry)
+	}
+
+	result := make([]extractedGroup, 0, len(order))
+	for _, key := range order {
+		result = append(result, *groups[key])
+	}
+	return result
+}
+
+// extractEntryLabels parses a single entry's LogLine as JSON and returns the
+// configured fields found in it, or nil if it isn't JSON or none matched.
+func (e *jsonFieldExtractor) extractEntryLabels(entry *LogEntry) map[string]string {
+	if entry == nil {
+		return nil
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal(entry.LogLine, &parsed); err != nil {
+		return nil
+	}
+
+	extracted := make(map[string]string)
+
+	if level, ok := firstStringField(parsed, e.levelFields); ok {
+		extracted[logLevelForcedLabel] = level
+	}
+
+	for jsonField, labelName := range e.labelFields {
+		if value, ok := stringField(parsed, jsonField); ok {
+			extracted[labelName] = value
+		}
+	}
+
+	if len(extracted) == 0 {
+		return nil
+	}
+	return extracted
+}
+
+// labelsKey builds a stable map key from a label set so identical extractions
+// land in the same group regardless of iteration order.
+func labelsKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+func firstStringField(parsed map[string]any, fields []string) (string, bool) {
+	for _, field := range fields {
+		if value, ok := stringField(parsed, field); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func stringField(parsed map[string]any, field string) (string, bool) {
+	value, ok := parsed[field].(string)
+	return value, ok && value != ""
+}
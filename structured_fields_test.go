@@ -0,0 +1,78 @@
+package promtail
+
+import "testing"
+
+func TestGroupByExtractedLabelsSplitsMixedSeverity(t *testing.T) {
+	e := &jsonFieldExtractor{levelFields: []string{"level"}}
+
+	infoEntry := &LogEntry{LogLine: []byte(`{"level":"info","msg":"starting"}`)}
+	errorEntry := &LogEntry{LogLine: []byte(`{"level":"error","msg":"boom"}`)}
+
+	groups := e.groupByExtractedLabels([]*LogEntry{infoEntry, errorEntry})
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups for mixed severities, got %d", len(groups))
+	}
+
+	if got := groups[0].extra[logLevelForcedLabel]; got != "info" {
+		t.Errorf("groups[0] level = %q, want info", got)
+	}
+	if got := groups[1].extra[logLevelForcedLabel]; got != "error" {
+		t.Errorf("groups[1] level = %q, want error", got)
+	}
+
+	if len(groups[0].entries) != 1 || groups[0].entries[0] != infoEntry {
+		t.Errorf("groups[0] entries = %v, want [infoEntry]", groups[0].entries)
+	}
+	if len(groups[1].entries) != 1 || groups[1].entries[0] != errorEntry {
+		t.Errorf("groups[1] entries = %v, want [errorEntry]", groups[1].entries)
+	}
+}
+
+func TestGroupByExtractedLabelsMergesSameSeverity(t *testing.T) {
+	e := &jsonFieldExtractor{levelFields: []string{"level"}}
+
+	entries := []*LogEntry{
+		{LogLine: []byte(`{"level":"info"}`)},
+		{LogLine: []byte(`{"level":"info"}`)},
+		{LogLine: []byte(`not json`)},
+	}
+
+	groups := e.groupByExtractedLabels(entries)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups (info, unparsed), got %d", len(groups))
+	}
+	if len(groups[0].entries) != 2 {
+		t.Errorf("expected both info entries in one group, got %d", len(groups[0].entries))
+	}
+}
+
+func TestGroupByExtractedLabelsUnconfiguredIsNoop(t *testing.T) {
+	e := &jsonFieldExtractor{}
+	entries := []*LogEntry{{LogLine: []byte(`{"level":"info"}`)}}
+
+	groups := e.groupByExtractedLabels(entries)
+	if len(groups) != 1 || len(groups[0].extra) != 0 {
+		t.Fatalf("expected a single passthrough group, got %v", groups)
+	}
+}
+
+func TestSetLabelFromJSONFieldPromotesField(t *testing.T) {
+	rcv := &lokiJsonV1Exchanger{fieldExtractor: &jsonFieldExtractor{}}
+	rcv.SetLabelFromJSONField("service", "service")
+
+	extracted := rcv.fieldExtractor.extractEntryLabels(&LogEntry{LogLine: []byte(`{"service":"api"}`)})
+	if extracted["service"] != "api" {
+		t.Fatalf("extracted = %v, want service=api", extracted)
+	}
+}
+
+func TestSetStructuredLevelExtractionDefaultsFields(t *testing.T) {
+	rcv := &lokiJsonV1Exchanger{fieldExtractor: &jsonFieldExtractor{}}
+	rcv.SetStructuredLevelExtraction()
+
+	extracted := rcv.fieldExtractor.extractEntryLabels(&LogEntry{LogLine: []byte(`{"severity":"warn"}`)})
+	if extracted[logLevelForcedLabel] != "warn" {
+		t.Fatalf("extracted = %v, want logLevel=warn via default severity field", extracted)
+	}
+}
@@ -0,0 +1,345 @@
+package promtail
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Tailer consumes logs from Loki, either by polling /loki/api/v1/query_range
+// over a historical window or by following /loki/api/v1/tail for live
+// streaming once the window is caught up.
+type Tailer interface {
+	Entries() <-chan *LogEntry
+	Errors() <-chan error
+	Close()
+}
+
+type TailerConfig struct {
+	LokiAddress string
+	Query       string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+	Headers     map[string]string
+
+	Username string
+	Password string
+
+	// Follow switches to the websocket /loki/api/v1/tail endpoint once
+	// query_range has caught up, i.e. a page returns fewer than Limit
+	// entries. When a full page moves the cursor to or past a non-zero
+	// Until, the tailer stops without following.
+	Follow bool
+
+	// WaitForReady polls /ready before starting to tail.
+	WaitForReady bool
+}
+
+const (
+	defaultTailerLimit       = 100
+	defaultReadyPollInterval = time.Second
+)
+
+// Creates a tailer pulling logs out of Loki via `query_range`, with an
+// optional websocket fallback for live streaming.
+//
+//	Read more at: https://github.com/grafana/loki/blob/master/docs/api.md#query-loki-over-a-range-of-time
+func NewLokiTailer(cfg TailerConfig) Tailer {
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultTailerLimit
+	}
+
+	t := &lokiTailer{
+		cfg:        cfg,
+		restClient: &http.Client{},
+		entries:    make(chan *LogEntry),
+		errors:     make(chan error, 1),
+		done:       make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+type lokiTailer struct {
+	cfg        TailerConfig
+	restClient *http.Client
+	entries    chan *LogEntry
+	errors     chan error
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (rcv *lokiTailer) Entries() <-chan *LogEntry {
+	return rcv.entries
+}
+
+func (rcv *lokiTailer) Errors() <-chan error {
+	return rcv.errors
+}
+
+func (rcv *lokiTailer) Close() {
+	rcv.closeOnce.Do(func() { close(rcv.done) })
+}
+
+func (rcv *lokiTailer) run() {
+	defer close(rcv.entries)
+
+	if rcv.cfg.WaitForReady {
+		if err := rcv.waitForReady(); err != nil {
+			rcv.emitError(err)
+			return
+		}
+	}
+
+	since := rcv.cfg.Since
+	for {
+		select {
+		case <-rcv.done:
+			return
+		default:
+		}
+
+		lastNano, err := rcv.queryRange(since)
+		if err != nil {
+			rcv.emitError(err)
+			return
+		}
+
+		if lastNano == 0 {
+			break
+		}
+		since = time.Unix(0, lastNano+1)
+
+		if !rcv.cfg.Until.IsZero() && !since.Before(rcv.cfg.Until) {
+			return
+		}
+	}
+
+	if rcv.cfg.Follow {
+		if err := rcv.tailWebsocket(since); err != nil {
+			rcv.emitError(err)
+		}
+	}
+}
+
+// queryRange fetches a single page starting at since and delivers its
+// entries on the entries channel. It returns the nanosecond timestamp of the
+// latest entry seen, or 0 if the page held fewer than Limit entries (meaning
+// the caller has caught up and can either stop or switch to following).
+func (rcv *lokiTailer) queryRange(since time.Time) (int64, error) {
+	query := url.Values{}
+	query.Set("query", rcv.cfg.Query)
+	query.Set("direction", "forward")
+	query.Set("limit", strconv.Itoa(rcv.cfg.Limit))
+	query.Set("start", strconv.FormatInt(since.UnixNano(), 10))
+	if !rcv.cfg.Until.IsZero() {
+		query.Set("end", strconv.FormatInt(rcv.cfg.Until.UnixNano(), 10))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rcv.cfg.LokiAddress+"/loki/api/v1/query_range?"+query.Encode(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %s", err)
+	}
+	rcv.applyAuth(req)
+
+	resp, err := rcv.restClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query range: %s", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		messageBody, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("unexpected response code [code=%d], message: %s",
+			resp.StatusCode, string(messageBody))
+	}
+
+	var dto lokiDTOQueryRangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&dto); err != nil {
+		return 0, fmt.Errorf("failed to decode query_range response: %s", err)
+	}
+
+	var lastNano int64
+	count := 0
+	for _, stream := range dto.Data.Result {
+		for _, value := range stream.Values {
+			nano, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+
+			entry := &LogEntry{
+				Labels:    stream.Stream,
+				Timestamp: time.Unix(0, nano),
+				LogLine:   []byte(value[1]),
+			}
+
+			select {
+			case rcv.entries <- entry:
+			case <-rcv.done:
+				return lastNano, nil
+			}
+
+			count++
+			if nano > lastNano {
+				lastNano = nano
+			}
+		}
+	}
+
+	if count < rcv.cfg.Limit {
+		return 0, nil
+	}
+
+	return lastNano, nil
+}
+
+func (rcv *lokiTailer) tailWebsocket(since time.Time) error {
+	query := url.Values{}
+	query.Set("query", rcv.cfg.Query)
+	query.Set("start", strconv.FormatInt(since.UnixNano(), 10))
+
+	wsAddress := wsURL(rcv.cfg.LokiAddress) + "/loki/api/v1/tail?" + query.Encode()
+
+	header := http.Header{}
+	for k, v := range rcv.cfg.Headers {
+		header.Set(k, v)
+	}
+	if rcv.cfg.Username != "" && rcv.cfg.Password != "" {
+		header.Set("Authorization", basicAuthHeader(rcv.cfg.Username, rcv.cfg.Password))
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsAddress, header)
+	if err != nil {
+		return fmt.Errorf("failed to dial tail websocket: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	go func() {
+		<-rcv.done
+		_ = conn.Close()
+	}()
+
+	for {
+		var dto lokiDTOTailResponse
+		if err := conn.ReadJSON(&dto); err != nil {
+			select {
+			case <-rcv.done:
+				return nil
+			default:
+				return fmt.Errorf("tail websocket closed: %s", err)
+			}
+		}
+
+		for _, stream := range dto.Streams {
+			for _, value := range stream.Values {
+				nano, err := strconv.ParseInt(value[0], 10, 64)
+				if err != nil {
+					continue
+				}
+
+				entry := &LogEntry{
+					Labels:    stream.Stream,
+					Timestamp: time.Unix(0, nano),
+					LogLine:   []byte(value[1]),
+				}
+
+				select {
+				case rcv.entries <- entry:
+				case <-rcv.done:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+func (rcv *lokiTailer) waitForReady() error {
+	for {
+		select {
+		case <-rcv.done:
+			return fmt.Errorf("tailer closed while waiting for ready")
+		default:
+		}
+
+		req, err := http.NewRequest(http.MethodGet, rcv.cfg.LokiAddress+"/ready", nil)
+		if err != nil {
+			return fmt.Errorf("unable to build ready request: %s", err)
+		}
+		rcv.applyAuth(req)
+
+		resp, err := rcv.restClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+		}
+
+		select {
+		case <-time.After(defaultReadyPollInterval):
+		case <-rcv.done:
+			return fmt.Errorf("tailer closed while waiting for ready")
+		}
+	}
+}
+
+func (rcv *lokiTailer) applyAuth(req *http.Request) {
+	for k, v := range rcv.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if rcv.cfg.Username != "" && rcv.cfg.Password != "" {
+		req.SetBasicAuth(rcv.cfg.Username, rcv.cfg.Password)
+	}
+}
+
+func (rcv *lokiTailer) emitError(err error) {
+	select {
+	case rcv.errors <- err:
+	default:
+	}
+}
+
+type (
+	lokiDTOQueryRangeResponse struct {
+		Data lokiDTOQueryRangeData `json:"data"`
+	}
+
+	lokiDTOQueryRangeData struct {
+		Result []lokiDTOQueryRangeStream `json:"result"`
+	}
+
+	lokiDTOQueryRangeStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	lokiDTOTailResponse struct {
+		Streams []lokiDTOQueryRangeStream `json:"streams"`
+	}
+)
+
+// wsURL rewrites a Loki http(s) address into the matching ws(s) one,
+// preserving TLS so an https LokiAddress doesn't get silently downgraded to
+// a plaintext websocket.
+func wsURL(address string) string {
+	switch {
+	case strings.HasPrefix(address, "https://"):
+		return "wss://" + strings.TrimPrefix(address, "https://")
+	case strings.HasPrefix(address, "http://"):
+		return "ws://" + strings.TrimPrefix(address, "http://")
+	default:
+		return "ws://" + address
+	}
+}
@@ -0,0 +1,158 @@
+package promtail
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWsURL(t *testing.T) {
+	cases := []struct {
+		address string
+		want    string
+	}{
+		{"https://loki.example.com", "wss://loki.example.com"},
+		{"http://loki.example.com", "ws://loki.example.com"},
+		{"loki.example.com", "ws://loki.example.com"},
+	}
+
+	for _, c := range cases {
+		if got := wsURL(c.address); got != c.want {
+			t.Errorf("wsURL(%q) = %q, want %q", c.address, got, c.want)
+		}
+	}
+}
+
+// TestQueryRangePaginationAndDelivery drives queryRange against a fake Loki
+// server that returns a full page (forcing another round) followed by a
+// short page (signalling the caller has caught up), and checks both the
+// cursor math and that every entry is actually delivered on the channel.
+func TestQueryRangePaginationAndDelivery(t *testing.T) {
+	const limit = 2
+
+	var gotStarts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		gotStarts = append(gotStarts, q.Get("start"))
+
+		var resp lokiDTOQueryRangeResponse
+		switch len(gotStarts) {
+		case 1:
+			resp.Data.Result = []lokiDTOQueryRangeStream{{
+				Stream: map[string]string{"job": "promtail"},
+				Values: [][2]string{{"100", "first"}, {"200", "second"}},
+			}}
+		default:
+			resp.Data.Result = []lokiDTOQueryRangeStream{{
+				Stream: map[string]string{"job": "promtail"},
+				Values: [][2]string{{"300", "third"}},
+			}}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	rcv := &lokiTailer{
+		cfg: TailerConfig{
+			LokiAddress: server.URL,
+			Query:       `{job="promtail"}`,
+			Limit:       limit,
+		},
+		restClient: &http.Client{},
+		entries:    make(chan *LogEntry, 3),
+		done:       make(chan struct{}),
+	}
+
+	lastNano, err := rcv.queryRange(time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("queryRange (page 1): %s", err)
+	}
+	if lastNano != 200 {
+		t.Fatalf("lastNano after a full page = %d, want 200", lastNano)
+	}
+
+	lastNano, err = rcv.queryRange(time.Unix(0, lastNano+1))
+	if err != nil {
+		t.Fatalf("queryRange (page 2): %s", err)
+	}
+	if lastNano != 0 {
+		t.Fatalf("lastNano after a short page = %d, want 0 (caught up)", lastNano)
+	}
+
+	if len(gotStarts) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(gotStarts))
+	}
+	if gotStarts[1] != "201" {
+		t.Fatalf("second request start = %q, want %q (lastNano+1)", gotStarts[1], "201")
+	}
+
+	close(rcv.entries)
+	var lines []string
+	for entry := range rcv.entries {
+		lines = append(lines, string(entry.LogLine))
+	}
+	want := []string{"first", "second", "third"}
+	if len(lines) != len(want) {
+		t.Fatalf("delivered entries = %v, want %v", lines, want)
+	}
+	for i, line := range lines {
+		if line != want[i] {
+			t.Errorf("entries[%d] = %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestQueryRangePassesQueryAndLimit(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(lokiDTOQueryRangeResponse{})
+	}))
+	defer server.Close()
+
+	rcv := &lokiTailer{
+		cfg: TailerConfig{
+			LokiAddress: server.URL,
+			Query:       `{job="promtail"}`,
+			Limit:       5,
+		},
+		restClient: &http.Client{},
+		entries:    make(chan *LogEntry, 1),
+		done:       make(chan struct{}),
+	}
+
+	if _, err := rcv.queryRange(time.Unix(0, 42)); err != nil {
+		t.Fatalf("queryRange: %s", err)
+	}
+
+	if gotQuery.Get("query") != `{job="promtail"}` {
+		t.Errorf("query = %q, want the configured LogQL", gotQuery.Get("query"))
+	}
+	if gotQuery.Get("limit") != "5" {
+		t.Errorf("limit = %q, want 5", gotQuery.Get("limit"))
+	}
+	if gotQuery.Get("start") != "42" {
+		t.Errorf("start = %q, want 42", gotQuery.Get("start"))
+	}
+}
+
+func TestLokiTailerCloseConcurrentIsSafe(t *testing.T) {
+	rcv := &lokiTailer{done: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rcv.Close()
+		}()
+	}
+	wg.Wait()
+}